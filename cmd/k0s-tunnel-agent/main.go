@@ -0,0 +1,45 @@
+// Copyright © 2022 Alibaba Group Holding Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Command k0s-tunnel-agent runs on a worker that cannot dial the controller VIP
+// directly. It is installed and supervised by the k0s Runtime (see
+// pkg/runtime/k0s/tunnel.go); see pkg/tunnel for the actual proxying logic.
+package main
+
+import (
+	"flag"
+
+	"github.com/sealerio/sealer/pkg/tunnel"
+	"github.com/sirupsen/logrus"
+)
+
+func main() {
+	server := flag.String("server", "", "controller-side tunnel server to dial, host:port")
+	listen := flag.String("listen", "127.0.0.1:6443", "local address to expose, proxying to the real API server")
+	// --token-file is accepted for parity with the systemd unit installTunnelAgent
+	// renders; today's tunnel has no separate auth handshake beyond TCP
+	// reachability, so it is unused. Kept so the flag set matches the unit without
+	// needing a coordinated flag-set/unit-template change later.
+	_ = flag.String("token-file", "", "unused, accepted for compatibility with installTunnelAgent's unit template")
+	flag.Parse()
+
+	if *server == "" {
+		logrus.Fatal("--server is required")
+	}
+
+	agent := &tunnel.Agent{ServerAddr: *server, Listen: *listen}
+	if err := agent.Run(); err != nil {
+		logrus.Fatalf("tunnel agent exited: %v", err)
+	}
+}
@@ -0,0 +1,41 @@
+// Copyright © 2022 Alibaba Group Holding Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Command k0s-tunnel-server runs on a controller, accepting connections from
+// k0s-tunnel-agent instances running on workers that cannot dial the controller
+// directly. It is installed and supervised by the k0s Runtime (see
+// pkg/runtime/k0s/tunnel.go); see pkg/tunnel for the actual proxying logic.
+package main
+
+import (
+	"flag"
+
+	"github.com/sealerio/sealer/pkg/tunnel"
+	"github.com/sirupsen/logrus"
+)
+
+func main() {
+	listen := flag.String("listen", "", "address to accept agent connections on, host:port")
+	upstream := flag.String("upstream", "127.0.0.1:6443", "the real API server to forward tunneled connections to")
+	flag.Parse()
+
+	if *listen == "" {
+		logrus.Fatal("--listen is required")
+	}
+
+	server := &tunnel.Server{Addr: *listen, Upstream: *upstream}
+	if err := server.ListenAndServe(); err != nil {
+		logrus.Fatalf("tunnel server exited: %v", err)
+	}
+}
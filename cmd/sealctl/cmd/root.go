@@ -0,0 +1,30 @@
+// Copyright © 2022 Alibaba Group Holding Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import "github.com/spf13/cobra"
+
+// NewSealctlCmd returns the sealctl root command with every subcommand group
+// registered.
+func NewSealctlCmd() *cobra.Command {
+	sealctlCmd := &cobra.Command{
+		Use:   "sealctl",
+		Short: "sealctl is a host-side helper sealer runs on cluster nodes",
+	}
+
+	sealctlCmd.AddCommand(NewStaticPodCmd())
+
+	return sealctlCmd
+}
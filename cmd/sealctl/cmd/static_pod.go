@@ -0,0 +1,81 @@
+// Copyright © 2022 Alibaba Group Holding Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"fmt"
+	"net"
+	"os"
+
+	"github.com/sealerio/sealer/pkg/runtime/k0s"
+	"github.com/spf13/cobra"
+)
+
+var (
+	lvscareVIP         string
+	lvscareVIPPort     string
+	lvscareControllers []string
+	lvscareOutput      string
+)
+
+// NewStaticPodCmd returns the `sealctl static-pod` command group, letting operators
+// regenerate static pod manifests out-of-band without a full join/upgrade.
+func NewStaticPodCmd() *cobra.Command {
+	staticPodCmd := &cobra.Command{
+		Use:   "static-pod",
+		Short: "regenerate static pod manifests consumed by the k0s runtime",
+	}
+	staticPodCmd.AddCommand(newLvscareCmd())
+	return staticPodCmd
+}
+
+func newLvscareCmd() *cobra.Command {
+	lvscareCmd := &cobra.Command{
+		Use:   "lvscare",
+		Short: "render the lvscare static pod manifest that load balances the k0s API server",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if len(lvscareControllers) == 0 {
+				return fmt.Errorf("at least one --controller is required")
+			}
+
+			controllers := make([]net.IP, 0, len(lvscareControllers))
+			for _, c := range lvscareControllers {
+				ip := net.ParseIP(c)
+				if ip == nil {
+					return fmt.Errorf("invalid controller IP %q", c)
+				}
+				controllers = append(controllers, ip)
+			}
+
+			manifest, err := k0s.NewStaticPodGenerator(lvscareVIP, lvscareVIPPort).Manifest(controllers)
+			if err != nil {
+				return err
+			}
+
+			if lvscareOutput == "" {
+				fmt.Println(string(manifest))
+				return nil
+			}
+			return os.WriteFile(lvscareOutput, manifest, 0644)
+		},
+	}
+
+	lvscareCmd.Flags().StringVar(&lvscareVIP, "vip", k0s.DefaultVIP, "virtual IP exposed by lvscare")
+	lvscareCmd.Flags().StringVar(&lvscareVIPPort, "vip-port", k0s.DefaultVIPPort, "port exposed on the virtual IP")
+	lvscareCmd.Flags().StringSliceVar(&lvscareControllers, "controller", nil, "real controller IP to load balance across, may be repeated")
+	lvscareCmd.Flags().StringVarP(&lvscareOutput, "output", "o", "", "write the manifest to this path instead of stdout, for dropping straight into /etc/k0s/manifests")
+
+	return lvscareCmd
+}
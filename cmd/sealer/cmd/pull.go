@@ -0,0 +1,47 @@
+// Copyright © 2022 Alibaba Group Holding Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"github.com/sealerio/sealer/pkg/define/options"
+	"github.com/sealerio/sealer/pkg/imageengine/buildah"
+	"github.com/spf13/cobra"
+)
+
+var pullOpts options.PullOptions
+
+// NewPullCmd returns the `sealer pull` command, which downloads a cluster image (or,
+// with --platforms/--all-platforms, every matching platform of a manifest list) into
+// local storage.
+func NewPullCmd() *cobra.Command {
+	pullCmd := &cobra.Command{
+		Use:   "pull [image]",
+		Short: "pull a cluster image from a registry",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			pullOpts.Image = args[0]
+			return (&buildah.Engine{}).Pull(&pullOpts)
+		},
+	}
+
+	pullCmd.Flags().StringVar(&pullOpts.Platform, "platform", "", "pull this platform (os/arch[/variant]) when neither --platforms nor --all-platforms is set")
+	pullCmd.Flags().StringSliceVar(&pullOpts.Platforms, "platforms", nil, "comma-separated platforms (os/arch[/variant]) to pull from a manifest list")
+	pullCmd.Flags().BoolVar(&pullOpts.AllPlatforms, "all-platforms", false, "pull every platform in a manifest list instead of just --platform/--platforms")
+	pullCmd.Flags().BoolVar(&pullOpts.AllTags, "all-tags", false, "pull all tagged images in the repository")
+	pullCmd.Flags().StringVar(&pullOpts.PullPolicy, "policy", "missing", "when to pull: always, missing, or never")
+	pullCmd.Flags().BoolVarP(&pullOpts.Quiet, "quiet", "q", false, "suppress pull progress output")
+
+	return pullCmd
+}
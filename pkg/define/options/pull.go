@@ -0,0 +1,34 @@
+// Copyright © 2022 Alibaba Group Holding Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package options
+
+// PullOptions carries the flags accepted by `sealer pull` down into
+// buildah.Engine.Pull.
+type PullOptions struct {
+	Image      string
+	Platform   string
+	PullPolicy string
+	Quiet      bool
+	// AllTags pulls every tag of Image instead of just the one named.
+	AllTags bool
+	// AllPlatforms pulls every platform in Image's manifest list instead of just
+	// Platform, aggregating the result into a local manifest list.
+	AllPlatforms bool
+	// Platforms restricts which platforms of Image's manifest list are pulled, as
+	// "os/arch[/variant]" strings. Setting Platforms (or AllPlatforms) routes the pull
+	// through the manifest-list path; with neither set, Pull takes the single-image
+	// path instead and only Platform is consulted.
+	Platforms []string
+}
@@ -27,7 +27,11 @@ import (
 
 	"github.com/containers/buildah"
 	"github.com/containers/buildah/define"
+	"github.com/containers/common/libimage"
 	"github.com/containers/common/pkg/auth"
+	"github.com/containers/image/v5/manifest"
+	"github.com/containers/image/v5/transports/alltransports"
+	imgspecv1 "github.com/opencontainers/image-spec/specs-go/v1"
 	"github.com/pkg/errors"
 )
 
@@ -42,6 +46,13 @@ func (engine *Engine) Pull(opts *options.PullOptions) error {
 		return err
 	}
 
+	// a manifest list needs every matching child image pulled and re-aggregated
+	// locally, which is different enough from a single-image pull to live in its own
+	// path.
+	if opts.AllPlatforms || len(opts.Platforms) > 0 {
+		return engine.pullManifestList(opts)
+	}
+
 	// we need to new a systemContext instead of taking the systemContext of engine,
 	// because pullOption does not export platform option
 	newSystemCxt := systemContext()
@@ -60,12 +71,11 @@ func (engine *Engine) Pull(opts *options.PullOptions) error {
 	options := buildah.PullOptions{
 		Store:         store,
 		SystemContext: newSystemCxt,
-		// consider export this option later
-		AllTags:      false,
-		ReportWriter: os.Stderr,
-		MaxRetries:   maxPullPushRetries,
-		RetryDelay:   pullPushRetryDelay,
-		PullPolicy:   policy,
+		AllTags:       opts.AllTags,
+		ReportWriter:  os.Stderr,
+		MaxRetries:    maxPullPushRetries,
+		RetryDelay:    pullPushRetryDelay,
+		PullPolicy:    policy,
 	}
 
 	if opts.Quiet {
@@ -79,3 +89,136 @@ func (engine *Engine) Pull(opts *options.PullOptions) error {
 	logrus.Infof("%s", id)
 	return nil
 }
+
+// pullManifestList resolves opts.Image as a manifest list and pulls every child image
+// matching opts.Platforms (or every child when opts.AllPlatforms is set), aggregating
+// the results into a local manifest list via libimage so the cluster image stays usable
+// across hosts without re-tagging.
+func (engine *Engine) pullManifestList(opts *options.PullOptions) error {
+	systemCxt := engine.SystemContext()
+	store := engine.ImageStore()
+
+	srcRef, err := alltransports.ParseImageName(opts.Image)
+	if err != nil {
+		srcRef, err = alltransports.ParseImageName(fmt.Sprintf("docker://%s", opts.Image))
+		if err != nil {
+			return errors.Errorf("failed to parse image reference %s: %v", opts.Image, err)
+		}
+	}
+
+	src, err := srcRef.NewImageSource(getContext(), systemCxt)
+	if err != nil {
+		return errors.Errorf("failed to inspect %s: %v", opts.Image, err)
+	}
+	defer src.Close()
+
+	rawManifest, manifestType, err := src.GetManifest(getContext(), nil)
+	if err != nil {
+		return errors.Errorf("failed to read manifest of %s: %v", opts.Image, err)
+	}
+	if !manifest.MIMETypeIsMultiImage(manifestType) {
+		return errors.Errorf("%s is not a manifest list, cannot pull with --platforms/--all-platforms", opts.Image)
+	}
+
+	list, err := manifest.ListFromBlob(rawManifest, manifestType)
+	if err != nil {
+		return errors.Errorf("failed to parse manifest list of %s: %v", opts.Image, err)
+	}
+
+	wantedPlatforms, err := parsePlatforms(opts.Platforms)
+	if err != nil {
+		return err
+	}
+
+	policy, ok := define.PolicyMap[opts.PullPolicy]
+	if !ok {
+		return fmt.Errorf("unsupported pull policy %q", opts.PullPolicy)
+	}
+
+	runtime, err := libimage.RuntimeFromStore(store, &libimage.RuntimeOptions{SystemContext: systemCxt})
+	if err != nil {
+		return errors.Errorf("failed to get image runtime: %v", err)
+	}
+
+	manifestList, err := runtime.LookupManifestList(opts.Image)
+	if err != nil {
+		manifestList, err = runtime.CreateManifestList(opts.Image)
+		if err != nil {
+			return errors.Errorf("failed to create manifest list %s: %v", opts.Image, err)
+		}
+	}
+
+	for _, instance := range list.Instances() {
+		instanceInfo, err := list.Instance(instance)
+		if err != nil {
+			return err
+		}
+		if !opts.AllPlatforms && !platformWanted(instanceInfo.ReadOnly.Platform, wantedPlatforms) {
+			continue
+		}
+
+		childSystemCxt := systemContext()
+		if instanceInfo.ReadOnly.Platform != nil {
+			childSystemCxt.OSChoice = instanceInfo.ReadOnly.Platform.OS
+			childSystemCxt.ArchitectureChoice = instanceInfo.ReadOnly.Platform.Architecture
+			childSystemCxt.VariantChoice = instanceInfo.ReadOnly.Platform.Variant
+		}
+
+		pullOptions := buildah.PullOptions{
+			Store:         store,
+			SystemContext: childSystemCxt,
+			AllTags:       opts.AllTags,
+			ReportWriter:  os.Stderr,
+			MaxRetries:    maxPullPushRetries,
+			RetryDelay:    pullPushRetryDelay,
+			PullPolicy:    policy,
+		}
+		if opts.Quiet {
+			pullOptions.ReportWriter = nil
+		}
+
+		id, err := buildah.Pull(getContext(), opts.Image, pullOptions)
+		if err != nil {
+			return errors.Errorf("failed to pull %s for %s: %v", opts.Image, instance, err)
+		}
+		logrus.Infof("pulled %s", id)
+
+		if _, err := manifestList.Add(getContext(), id, nil); err != nil {
+			return errors.Errorf("failed to add %s to manifest list %s: %v", id, opts.Image, err)
+		}
+	}
+
+	logrus.Infof("%s", manifestList.ID())
+	return nil
+}
+
+// parsePlatforms turns "os/arch[/variant]" strings from --platforms into platform
+// descriptors for matching against manifest list instances.
+func parsePlatforms(platforms []string) ([]imgspecv1.Platform, error) {
+	parsed := make([]imgspecv1.Platform, 0, len(platforms))
+	for _, p := range platforms {
+		_os, arch, variant, err := parse.Platform(p)
+		if err != nil {
+			return nil, errors.Errorf("failed to parse platform %s: %v", p, err)
+		}
+		parsed = append(parsed, imgspecv1.Platform{OS: _os, Architecture: arch, Variant: variant})
+	}
+	return parsed, nil
+}
+
+// platformWanted reports whether platform matches one of wanted. An empty wanted list
+// matches everything.
+func platformWanted(platform *imgspecv1.Platform, wanted []imgspecv1.Platform) bool {
+	if len(wanted) == 0 {
+		return true
+	}
+	if platform == nil {
+		return false
+	}
+	for _, w := range wanted {
+		if platform.OS == w.OS && platform.Architecture == w.Architecture && (w.Variant == "" || platform.Variant == w.Variant) {
+			return true
+		}
+	}
+	return false
+}
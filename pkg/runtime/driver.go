@@ -0,0 +1,40 @@
+// Copyright © 2022 Alibaba Group Holding Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package runtime
+
+import "net"
+
+// Metadata carries the k8s distribution/version info a Runtime reports back to
+// sealer for building/tagging the cluster image.
+type Metadata struct {
+	Version        string
+	ClusterRuntime string
+}
+
+// Installer is the interface every Runtime implementation (k0s, k3s, ...) satisfies,
+// so sealer can drive cluster lifecycle without depending on the concrete runtime.
+type Installer interface {
+	Init() error
+	Upgrade() error
+	Reset() error
+	JoinMasters(newMastersIPList []net.IP) error
+	JoinNodes(newNodesIPList []net.IP) error
+	DeleteMasters(mastersIPList []net.IP) error
+	DeleteNodes(nodesIPList []net.IP) error
+	GetClusterMetadata() (*Metadata, error)
+	// UpdateCert adds altNames (DNS names or IPs) to the API server serving cert's SAN
+	// list without recreating the cluster.
+	UpdateCert(altNames []string) error
+}
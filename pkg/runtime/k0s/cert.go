@@ -0,0 +1,188 @@
+// Copyright © 2022 Alibaba Group Holding Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package k0s
+
+import (
+	"fmt"
+	"net"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	// k0sPKIDir is where k0s stores the cluster CA and serving certs/keys.
+	k0sPKIDir = "/var/lib/k0s/pki"
+	// k0sAdminConf is the kubeconfig k0s generates for cluster-admin access.
+	k0sAdminConf = k0sPKIDir + "/admin.conf"
+	// k0sPKIBackupDir is where updateCert stashes the previous PKI directory before
+	// regenerating certs, so a failed rotation can be rolled back by hand.
+	k0sPKIBackupDir = "/var/lib/k0s/pki.bak"
+	// k0sConfigPath is the k0s controller config k0s reads its API server SANs from.
+	k0sConfigPath = "/etc/k0s/k0s.yaml"
+	// certReadyRetries/certReadyInterval bound how long updateCert waits for a
+	// restarted controller to report healthy before moving on to the next one.
+	certReadyRetries  = 30
+	certReadyInterval = 2 * time.Second
+)
+
+// updateCert regenerates the k0s API server serving certificate one controller at a
+// time, merging altNames (extra DNS names / IPs, such as a newly provisioned VIP or an
+// external load balancer hostname) into the SAN list already persisted on the cluster.
+// Controllers are rotated sequentially, waiting for each one to come back healthy
+// before touching the next, so the control plane never loses quorum entirely during
+// the rotation. The resulting admin.conf is re-synced to the certs dir on the host
+// running sealer once every controller is done.
+func (k *Runtime) updateCert(altNames []string) error {
+	controllers := k.cluster.GetMasterIPList()
+	if len(controllers) == 0 {
+		return fmt.Errorf("no controller found to update cert on")
+	}
+
+	sans := mergeSANs(k.cluster.Spec.SANs, altNames)
+
+	for _, host := range controllers {
+		if err := k.rotateControllerCert(host, sans); err != nil {
+			return err
+		}
+	}
+
+	// record the merged SAN list in k.cluster.Spec.SANs, which the next updateCert
+	// starts from. Only updateCert reads it: a controller joined later through
+	// JoinCommand writes a fresh `k0s config create` config without these SANs.
+	k.cluster.Spec.SANs = sans
+
+	return k.syncAdminConf(k.cluster.GetMaster0IP())
+}
+
+// rotateControllerCert regenerates the kube-apiserver serving cert on a single
+// controller and restarts k0s there before returning. k0s has no "rotate with these
+// SANs" subcommand; the supported way to change the apiserver cert's SAN list is to
+// merge the names into spec.api.sans in k0s.yaml and let k0s regenerate any cert that
+// no longer matches its config on the next start, so that is what this does: merge
+// sans into the existing config, back up the PKI dir, drop the now-stale apiserver
+// serving cert/key pair (server.crt/server.key; k0s's own CA and client certs are left
+// alone), and restart.
+func (k *Runtime) rotateControllerCert(host net.IP, sans []string) error {
+	if err := k.mergeAPISans(host, sans); err != nil {
+		return fmt.Errorf("failed to update %s on %s: %v", k0sConfigPath, host, err)
+	}
+
+	cmds := []string{
+		fmt.Sprintf("cp -r %s %s", k0sPKIDir, k0sPKIBackupDir),
+		fmt.Sprintf("rm -f %s/server.crt %s/server.key", k0sPKIDir, k0sPKIDir),
+		"k0s stop",
+		"k0s start",
+	}
+	for _, cmd := range cmds {
+		if _, err := k.Runner.Run(host, cmd); err != nil {
+			return fmt.Errorf("failed to rotate cert on %s: %v", host, err)
+		}
+	}
+	if err := k.waitControllerReady(host); err != nil {
+		return fmt.Errorf("controller %s did not come back healthy after cert rotation: %v", host, err)
+	}
+	logrus.Infof("rotated k0s API server cert on %s with SANs %v", host, sans)
+	return nil
+}
+
+// mergeAPISans reads k0sConfigPath from host, merges sans into whatever
+// spec.api.sans is already configured there (de-duplicating, preserving order), and
+// writes the result back. A real k0s.yaml always has a spec.api block already (it
+// carries spec.api.address), so this has to merge into it rather than blindly
+// appending a new one; doing so also makes rotateControllerCert safe to run more than
+// once.
+func (k *Runtime) mergeAPISans(host net.IP, sans []string) error {
+	out, err := k.Runner.Run(host, fmt.Sprintf("cat %s", k0sConfigPath))
+	if err != nil {
+		return fmt.Errorf("failed to read config: %v", err)
+	}
+
+	var cfg map[string]interface{}
+	if err := yaml.Unmarshal(out, &cfg); err != nil {
+		return fmt.Errorf("failed to parse config: %v", err)
+	}
+	if cfg == nil {
+		cfg = map[string]interface{}{}
+	}
+
+	spec, _ := cfg["spec"].(map[string]interface{})
+	if spec == nil {
+		spec = map[string]interface{}{}
+	}
+	api, _ := spec["api"].(map[string]interface{})
+	if api == nil {
+		api = map[string]interface{}{}
+	}
+
+	api["sans"] = mergeSANs(stringSlice(api["sans"]), sans)
+	spec["api"] = api
+	cfg["spec"] = spec
+
+	merged, err := yaml.Marshal(cfg)
+	if err != nil {
+		return fmt.Errorf("failed to render config: %v", err)
+	}
+
+	_, err = k.Runner.Run(host, fmt.Sprintf("cat > %s <<'EOF'\n%s\nEOF", k0sConfigPath, string(merged)))
+	return err
+}
+
+// stringSlice converts a decoded YAML sequence (a []interface{} of strings) to
+// []string, ignoring anything that is not present or not a string.
+func stringSlice(v interface{}) []string {
+	raw, _ := v.([]interface{})
+	out := make([]string, 0, len(raw))
+	for _, r := range raw {
+		if s, ok := r.(string); ok {
+			out = append(out, s)
+		}
+	}
+	return out
+}
+
+// waitControllerReady polls k0s status on host until it reports running, so
+// updateCert only moves on to the next controller once this one is back.
+func (k *Runtime) waitControllerReady(host net.IP) error {
+	for i := 0; i < certReadyRetries; i++ {
+		if _, err := k.Runner.Run(host, "k0s status"); err == nil {
+			return nil
+		}
+		time.Sleep(certReadyInterval)
+	}
+	return fmt.Errorf("timed out waiting for k0s to become ready")
+}
+
+// syncAdminConf pulls the freshly regenerated admin.conf from master0 back onto the
+// host running sealer, so kubectl/sealer keep working against the rotated cert.
+func (k *Runtime) syncAdminConf(master0 net.IP) error {
+	return k.Runner.CopyR(master0, k0sAdminConf, k.getCertsDir())
+}
+
+// mergeSANs merges existing and new SAN entries, de-duplicating while preserving
+// order.
+func mergeSANs(existing, extra []string) []string {
+	seen := make(map[string]bool, len(existing)+len(extra))
+	merged := make([]string, 0, len(existing)+len(extra))
+	for _, s := range append(existing, extra...) {
+		if s == "" || seen[s] {
+			continue
+		}
+		seen[s] = true
+		merged = append(merged, s)
+	}
+	return merged
+}
@@ -0,0 +1,63 @@
+// Copyright © 2022 Alibaba Group Holding Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package k0s
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"regexp"
+)
+
+// serverAddrPattern matches the "server: https://host:port" line inside the kubeconfig
+// a k0s join token decodes to.
+var serverAddrPattern = regexp.MustCompile(`server: https://\S+`)
+
+// rewriteJoinTokenServerAddress decodes a k0s join token, rewrites the embedded
+// kubeconfig "server:" entry to host:port, and re-encodes it. This is what lets
+// workers join through the lvscare VIP (or, when enabled, the local tunnel agent)
+// instead of master0 directly.
+func rewriteJoinTokenServerAddress(token []byte, host, port string) ([]byte, error) {
+	raw, err := base64.StdEncoding.DecodeString(string(token))
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode join token: %v", err)
+	}
+
+	gr, err := gzip.NewReader(bytes.NewReader(raw))
+	if err != nil {
+		return nil, fmt.Errorf("failed to open join token: %v", err)
+	}
+	defer gr.Close()
+
+	kubeconfig, err := io.ReadAll(gr)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read join token: %v", err)
+	}
+
+	rewritten := serverAddrPattern.ReplaceAll(kubeconfig, []byte(fmt.Sprintf("server: https://%s:%s", host, port)))
+
+	var buf bytes.Buffer
+	gw := gzip.NewWriter(&buf)
+	if _, err := gw.Write(rewritten); err != nil {
+		return nil, fmt.Errorf("failed to re-encode join token: %v", err)
+	}
+	if err := gw.Close(); err != nil {
+		return nil, fmt.Errorf("failed to re-encode join token: %v", err)
+	}
+
+	return []byte(base64.StdEncoding.EncodeToString(buf.Bytes())), nil
+}
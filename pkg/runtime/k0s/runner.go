@@ -0,0 +1,179 @@
+// Copyright © 2022 Alibaba Group Holding Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package k0s
+
+import (
+	"net"
+	"os/exec"
+	"strings"
+
+	v2 "github.com/sealerio/sealer/types/api/v2"
+	"github.com/sealerio/sealer/utils/ssh"
+)
+
+// CommandRunner abstracts how a command actually reaches a host, so Runtime is not
+// hard-wired to SSH. FakeRunner makes the command sequences produced by JoinCommand,
+// getKubeVersion, etc. unit-testable, and ExecRunner unlocks non-SSH deployment modes
+// (local single-node, nsenter-into-container for k0s-in-docker). A future agent-tunnel
+// transport is just another implementation of this interface.
+type CommandRunner interface {
+	// Run executes cmd on host and returns its output.
+	Run(host net.IP, cmd string) ([]byte, error)
+	// RunWithSplit executes cmd on host and returns the output split on split.
+	RunWithSplit(host net.IP, cmd, split string) (string, error)
+	// Copy copies the local src path to dst on host.
+	Copy(host net.IP, src, dst string) error
+	// CopyR copies src on host to the local dst path.
+	CopyR(host net.IP, src, dst string) error
+	// Ping checks that host is reachable.
+	Ping(host net.IP) error
+}
+
+// SSHRunner is the default CommandRunner, wrapping today's ssh.Interface.
+type SSHRunner struct {
+	cluster *v2.Cluster
+}
+
+// NewSSHRunner returns a CommandRunner that dials hosts over SSH using cluster's
+// connection settings.
+func NewSSHRunner(cluster *v2.Cluster) *SSHRunner {
+	return &SSHRunner{cluster: cluster}
+}
+
+func (r *SSHRunner) client(host net.IP) (ssh.Interface, error) {
+	return ssh.NewStdoutSSHClient(host, r.cluster)
+}
+
+func (r *SSHRunner) Run(host net.IP, cmd string) ([]byte, error) {
+	client, err := r.client(host)
+	if err != nil {
+		return nil, err
+	}
+	return client.Cmd(host, cmd)
+}
+
+func (r *SSHRunner) RunWithSplit(host net.IP, cmd, split string) (string, error) {
+	client, err := r.client(host)
+	if err != nil {
+		return "", err
+	}
+	return client.CmdToString(host, cmd, split)
+}
+
+func (r *SSHRunner) Copy(host net.IP, src, dst string) error {
+	client, err := r.client(host)
+	if err != nil {
+		return err
+	}
+	return client.Copy(host, src, dst)
+}
+
+func (r *SSHRunner) CopyR(host net.IP, src, dst string) error {
+	client, err := r.client(host)
+	if err != nil {
+		return err
+	}
+	return client.CopyR(host, src, dst)
+}
+
+func (r *SSHRunner) Ping(host net.IP) error {
+	client, err := r.client(host)
+	if err != nil {
+		return err
+	}
+	return client.Ping(host)
+}
+
+// ExecRunner runs commands locally via os/exec, for when master0 is the host sealer
+// itself is running on (single-node deployments, or nsenter-into-container for
+// k0s-in-docker). host is accepted for interface compatibility but otherwise unused.
+type ExecRunner struct{}
+
+// NewExecRunner returns a CommandRunner that always executes locally.
+func NewExecRunner() *ExecRunner {
+	return &ExecRunner{}
+}
+
+func (r *ExecRunner) Run(_ net.IP, cmd string) ([]byte, error) {
+	return exec.Command("sh", "-c", cmd).CombinedOutput()
+}
+
+func (r *ExecRunner) RunWithSplit(host net.IP, cmd, split string) (string, error) {
+	out, err := r.Run(host, cmd)
+	if err != nil {
+		return "", err
+	}
+	return strings.Split(string(out), split)[0], nil
+}
+
+func (r *ExecRunner) Copy(_ net.IP, src, dst string) error {
+	return exec.Command("cp", "-r", src, dst).Run()
+}
+
+func (r *ExecRunner) CopyR(_ net.IP, src, dst string) error {
+	return exec.Command("cp", "-r", src, dst).Run()
+}
+
+func (r *ExecRunner) Ping(_ net.IP) error {
+	return nil
+}
+
+// FakeRunner is an in-memory CommandRunner for unit tests. It records every command it
+// is asked to run, keyed by host, and returns canned output/errors set up by the test.
+type FakeRunner struct {
+	Commands map[string][]string
+	Output   map[string][]byte
+	Errs     map[string]error
+}
+
+// NewFakeRunner returns an empty FakeRunner ready to be seeded with Output/Errs.
+func NewFakeRunner() *FakeRunner {
+	return &FakeRunner{
+		Commands: map[string][]string{},
+		Output:   map[string][]byte{},
+		Errs:     map[string]error{},
+	}
+}
+
+func (r *FakeRunner) Run(host net.IP, cmd string) ([]byte, error) {
+	r.Commands[host.String()] = append(r.Commands[host.String()], cmd)
+	if err, ok := r.Errs[cmd]; ok {
+		return nil, err
+	}
+	return r.Output[cmd], nil
+}
+
+func (r *FakeRunner) RunWithSplit(host net.IP, cmd, split string) (string, error) {
+	out, err := r.Run(host, cmd)
+	if err != nil {
+		return "", err
+	}
+	return strings.Split(string(out), split)[0], nil
+}
+
+func (r *FakeRunner) Copy(host net.IP, src, dst string) error {
+	r.Commands[host.String()] = append(r.Commands[host.String()], "copy "+src+" -> "+dst)
+	return nil
+}
+
+func (r *FakeRunner) CopyR(host net.IP, src, dst string) error {
+	r.Commands[host.String()] = append(r.Commands[host.String()], "copyr "+src+" -> "+dst)
+	return nil
+}
+
+func (r *FakeRunner) Ping(host net.IP) error {
+	r.Commands[host.String()] = append(r.Commands[host.String()], "ping")
+	return nil
+}
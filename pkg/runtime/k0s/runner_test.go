@@ -0,0 +1,262 @@
+// Copyright © 2022 Alibaba Group Holding Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package k0s
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/base64"
+	"fmt"
+	"net"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/sealerio/sealer/pkg/registry"
+	v2 "github.com/sealerio/sealer/types/api/v2"
+	"gopkg.in/yaml.v3"
+)
+
+func TestJoinCommand(t *testing.T) {
+	k := &Runtime{RegConfig: &registry.Config{Domain: "sea.hub", Port: "5000"}}
+
+	tests := []struct {
+		name string
+		role string
+		want []string
+	}{
+		{
+			name: "worker",
+			role: WorkerRole,
+			want: []string{
+				fmt.Sprintf("k0s install worker --cri-socket %s --token-file %s --kubelet-extra-args=\"--pod-manifest-path=%s\"",
+					ExternalCRI, DefaultK0sWorkerJoin, staticPodManifestDir),
+				"k0s start",
+			},
+		},
+		{
+			name: "unknown role",
+			role: "bogus",
+			want: nil,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := k.JoinCommand(tt.role)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("JoinCommand(%q) = %v, want %v", tt.role, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestGetKubeVersionFromHost(t *testing.T) {
+	host := net.ParseIP("192.168.0.2")
+
+	tests := []struct {
+		name    string
+		output  []byte
+		err     error
+		want    string
+		wantErr bool
+	}{
+		{
+			name:   "strips the +k0s suffix",
+			output: []byte("v1.23.1+k0s"),
+			want:   "v1.23.1",
+		},
+		{
+			name:    "propagates run errors",
+			err:     fmt.Errorf("connection refused"),
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			runner := NewFakeRunner()
+			if tt.err != nil {
+				runner.Errs[VersionCmd] = tt.err
+			} else {
+				runner.Output[VersionCmd] = tt.output
+			}
+
+			k := &Runtime{Runner: runner}
+			got, err := k.getKubeVersionFromHost(host)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("getKubeVersionFromHost() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err == nil && got != tt.want {
+				t.Errorf("getKubeVersionFromHost() = %q, want %q", got, tt.want)
+			}
+
+			want := []string{VersionCmd}
+			if got := runner.Commands[host.String()]; !reflect.DeepEqual(got, want) {
+				t.Errorf("unexpected command sequence: got %v, want %v", got, want)
+			}
+		})
+	}
+}
+
+func TestCopyWorkerJoinTokenViaVIP(t *testing.T) {
+	master0 := net.ParseIP("192.168.0.2")
+	worker := net.ParseIP("192.168.0.3")
+	joinCertPath := DefaultK0sWorkerJoin
+
+	token := encodeFakeToken(fmt.Sprintf("server: https://%s:6443", master0))
+
+	runner := NewFakeRunner()
+	runner.Output[fmt.Sprintf("cat %s", joinCertPath)] = token
+
+	k := &Runtime{Runner: runner, lvscare: NewStaticPodGenerator(DefaultVIP, DefaultVIPPort)}
+
+	if err := k.copyWorkerJoinTokenViaVIP(worker, master0, joinCertPath); err != nil {
+		t.Fatalf("copyWorkerJoinTokenViaVIP() error = %v", err)
+	}
+
+	cmds := runner.Commands[worker.String()]
+	if len(cmds) != 1 {
+		t.Fatalf("expected exactly one command on the worker, got %v", cmds)
+	}
+	rewritten, err := rewriteJoinTokenServerAddress(token, k.lvscare.VIP, k.lvscare.Port)
+	if err != nil {
+		t.Fatalf("failed to build expected token: %v", err)
+	}
+	want := fmt.Sprintf("cat > %s <<'EOF'\n%s\nEOF", joinCertPath, string(rewritten))
+	if cmds[0] != want {
+		t.Errorf("copyWorkerJoinTokenViaVIP() wrote %q, want %q", cmds[0], want)
+	}
+}
+
+func TestRotateControllerCert(t *testing.T) {
+	host := net.ParseIP("192.168.0.2")
+	sans := []string{"10.103.97.2", "lb.example.com"}
+
+	// a realistic k0s.yaml already has spec.api.address and one SAN configured;
+	// rotateControllerCert must merge into this, not replace or duplicate it.
+	initial := "apiVersion: k0s.k0sproject.io/v1beta1\nkind: ClusterConfig\nspec:\n  api:\n    address: 192.168.0.2\n    sans:\n      - 10.103.97.2\n"
+
+	runner := NewFakeRunner()
+	runner.Output[fmt.Sprintf("cat %s", k0sConfigPath)] = []byte(initial)
+	k := &Runtime{Runner: runner}
+
+	if err := k.rotateControllerCert(host, sans); err != nil {
+		t.Fatalf("rotateControllerCert() error = %v", err)
+	}
+
+	var cfg map[string]interface{}
+	if err := yaml.Unmarshal([]byte(initial), &cfg); err != nil {
+		t.Fatalf("failed to parse fixture: %v", err)
+	}
+	api := cfg["spec"].(map[string]interface{})["api"].(map[string]interface{})
+	api["sans"] = mergeSANs(stringSlice(api["sans"]), sans)
+	merged, err := yaml.Marshal(cfg)
+	if err != nil {
+		t.Fatalf("failed to render expected config: %v", err)
+	}
+
+	want := []string{
+		fmt.Sprintf("cat %s", k0sConfigPath),
+		fmt.Sprintf("cat > %s <<'EOF'\n%s\nEOF", k0sConfigPath, string(merged)),
+		fmt.Sprintf("cp -r %s %s", k0sPKIDir, k0sPKIBackupDir),
+		fmt.Sprintf("rm -f %s/server.crt %s/server.key", k0sPKIDir, k0sPKIDir),
+		"k0s stop",
+		"k0s start",
+		"k0s status",
+	}
+	if got := runner.Commands[host.String()]; !reflect.DeepEqual(got, want) {
+		t.Errorf("unexpected command sequence: got %v, want %v", got, want)
+	}
+
+	// a second rotation over the same (now-merged) config must not duplicate the
+	// spec.api block or re-append SANs already present.
+	runner2 := NewFakeRunner()
+	runner2.Output[fmt.Sprintf("cat %s", k0sConfigPath)] = merged
+	k2 := &Runtime{Runner: runner2}
+	if err := k2.rotateControllerCert(host, sans); err != nil {
+		t.Fatalf("second rotateControllerCert() error = %v", err)
+	}
+	cmds2 := runner2.Commands[host.String()]
+	writePrefix := fmt.Sprintf("cat > %s <<'EOF'\n", k0sConfigPath)
+	if len(cmds2) < 2 || !strings.HasPrefix(cmds2[1], writePrefix) {
+		t.Fatalf("second rotation did not write %s: %v", k0sConfigPath, cmds2)
+	}
+	written := strings.TrimSuffix(strings.TrimPrefix(cmds2[1], writePrefix), "\nEOF")
+	var cfg2 map[string]interface{}
+	if err := yaml.Unmarshal([]byte(written), &cfg2); err != nil {
+		t.Fatalf("failed to parse merged config: %v", err)
+	}
+	if _, dup := cfg2["api"]; dup {
+		t.Errorf("second rotation produced a duplicate top-level api key: %v", cfg2)
+	}
+	api2 := cfg2["spec"].(map[string]interface{})["api"].(map[string]interface{})
+	if got := stringSlice(api2["sans"]); !reflect.DeepEqual(got, sans) {
+		t.Errorf("second rotation changed sans = %v, want unchanged %v", got, sans)
+	}
+}
+
+func TestUpdateCertRotatesControllersOneAtATime(t *testing.T) {
+	master0 := net.ParseIP("192.168.0.2")
+	master1 := net.ParseIP("192.168.0.3")
+
+	runner := NewFakeRunner()
+	k := &Runtime{
+		Runner: runner,
+		cluster: &v2.Cluster{
+			Spec: v2.ClusterSpec{
+				SANs: []string{"10.103.97.2"},
+				Hosts: []v2.Host{
+					{IPS: []net.IP{master0, master1}, Roles: []string{v2.MASTER}},
+				},
+			},
+		},
+	}
+
+	if err := k.updateCert([]string{"lb.example.com"}); err != nil {
+		t.Fatalf("updateCert() error = %v", err)
+	}
+
+	wantSANs := []string{"10.103.97.2", "lb.example.com"}
+	if !reflect.DeepEqual(k.cluster.Spec.SANs, wantSANs) {
+		t.Errorf("Spec.SANs = %v, want %v", k.cluster.Spec.SANs, wantSANs)
+	}
+
+	// every controller must have seen its own full rotation sequence (7 commands);
+	// master0 additionally serves the final admin.conf sync back to the certs dir.
+	wantCmds := map[string]int{master0.String(): 8, master1.String(): 7}
+	for _, host := range []net.IP{master0, master1} {
+		cmds := runner.Commands[host.String()]
+		if len(cmds) != wantCmds[host.String()] {
+			t.Errorf("host %s: got %d commands, want %d (full rotation sequence): %v", host, len(cmds), wantCmds[host.String()], cmds)
+		}
+	}
+	if cmds := runner.Commands[master0.String()]; len(cmds) == 8 && !strings.HasPrefix(cmds[7], "copyr "+k0sAdminConf+" -> ") {
+		t.Errorf("master0: last command = %q, want the admin.conf sync", cmds[7])
+	}
+}
+
+// encodeFakeToken builds a fake k0s join token (base64+gzip kubeconfig) for tests.
+func encodeFakeToken(kubeconfig string) []byte {
+	var buf bytes.Buffer
+	gw := gzip.NewWriter(&buf)
+	if _, err := gw.Write([]byte(kubeconfig)); err != nil {
+		panic(err)
+	}
+	if err := gw.Close(); err != nil {
+		panic(err)
+	}
+	return []byte(base64.StdEncoding.EncodeToString(buf.Bytes()))
+}
@@ -18,6 +18,8 @@ import (
 	"context"
 	"fmt"
 	"net"
+	"os"
+	"path/filepath"
 	"strings"
 	"time"
 
@@ -26,7 +28,6 @@ import (
 	"github.com/sealerio/sealer/pkg/runtime"
 	v2 "github.com/sealerio/sealer/types/api/v2"
 	"github.com/sealerio/sealer/utils/platform"
-	"github.com/sealerio/sealer/utils/ssh"
 
 	"github.com/sirupsen/logrus"
 	"golang.org/x/sync/errgroup"
@@ -38,6 +39,23 @@ type Runtime struct {
 	cluster   *v2.Cluster
 	Vlog      int
 	RegConfig *registry.Config
+	// lvscare renders the static pod manifest workers use to load balance across all
+	// controllers through a single VIP instead of dialing master0 directly.
+	lvscare *StaticPodGenerator
+	// Runner executes commands on hosts. Defaults to SSHRunner; overridden in tests
+	// with FakeRunner, and for non-SSH deployment modes with ExecRunner.
+	Runner CommandRunner
+}
+
+// Option configures a Runtime at construction time.
+type Option func(*Runtime)
+
+// WithRunner overrides the default SSHRunner, primarily for tests and alternative
+// deployment modes (local single-node, nsenter-into-container, agent-tunnel).
+func WithRunner(runner CommandRunner) Option {
+	return func(k *Runtime) {
+		k.Runner = runner
+	}
 }
 
 func (k *Runtime) Init() error {
@@ -57,20 +75,45 @@ func (k *Runtime) JoinMasters(newMastersIPList []net.IP) error {
 	if len(newMastersIPList) != 0 {
 		logrus.Infof("%s will be added as master", newMastersIPList)
 	}
-	return k.joinMasters(newMastersIPList)
+	if err := k.joinMasters(newMastersIPList); err != nil {
+		return err
+	}
+	if tunnel := k.tunnelConfig(); tunnel.Enabled {
+		for _, host := range newMastersIPList {
+			if err := k.installTunnelServer(host, tunnel); err != nil {
+				return err
+			}
+		}
+	}
+	// the controller set changed, so every worker's lvscare static pod needs the new
+	// backend list.
+	return k.reconcileLvscareManifests(k.cluster.GetNodeIPList())
 }
 
 func (k *Runtime) JoinNodes(newNodesIPList []net.IP) error {
 	if len(newNodesIPList) != 0 {
 		logrus.Infof("%s will be added as worker", newNodesIPList)
 	}
+	if err := k.reconcileLvscareManifests(newNodesIPList); err != nil {
+		return fmt.Errorf("failed to generate lvscare static pod: %v", err)
+	}
+	if tunnel := k.tunnelConfig(); tunnel.Enabled {
+		for _, host := range newNodesIPList {
+			if err := k.installTunnelAgent(host, tunnel); err != nil {
+				return err
+			}
+		}
+	}
 	return k.joinNodes(newNodesIPList)
 }
 
 func (k *Runtime) DeleteMasters(mastersIPList []net.IP) error {
 	if len(mastersIPList) != 0 {
 		logrus.Infof("master %s will be deleted", mastersIPList)
-		return k.deleteMasters(mastersIPList)
+		if err := k.deleteMasters(mastersIPList); err != nil {
+			return err
+		}
+		return k.reconcileLvscareManifests(k.cluster.GetNodeIPList())
 	}
 	return nil
 }
@@ -87,15 +130,28 @@ func (k *Runtime) GetClusterMetadata() (*runtime.Metadata, error) {
 	return k.getClusterMetadata()
 }
 
+// UpdateCert adds altNames (DNS names or IPs, e.g. a newly provisioned VIP or an
+// external load balancer hostname) to the k0s API server serving cert's SAN list
+// without recreating the cluster.
+func (k *Runtime) UpdateCert(altNames []string) error {
+	return k.updateCert(altNames)
+}
+
 // NewK0sRuntime arg "clusterConfig" is the k0s config file under etc/${ant_name.yaml}, runtime need read k0s config from it
 // Mount image is required before new Runtime.
-func NewK0sRuntime(cluster *v2.Cluster) (runtime.Installer, error) {
-	return newK0sRuntime(cluster)
+func NewK0sRuntime(cluster *v2.Cluster, opts ...Option) (runtime.Installer, error) {
+	return newK0sRuntime(cluster, opts...)
 }
 
-func newK0sRuntime(cluster *v2.Cluster) (runtime.Installer, error) {
+func newK0sRuntime(cluster *v2.Cluster, opts ...Option) (runtime.Installer, error) {
 	k := &Runtime{
 		cluster: cluster,
+		lvscare: NewStaticPodGenerator(DefaultVIP, DefaultVIPPort),
+		Runner:  NewSSHRunner(cluster),
+	}
+
+	for _, opt := range opts {
+		opt(k)
 	}
 
 	k.RegConfig = registry.GetConfig(k.getImageMountDir(), k.cluster.GetMaster0IP())
@@ -132,11 +188,6 @@ func (k *Runtime) getImageMountDir() string {
 	return platform.DefaultMountClusterImageDir(k.cluster.Name)
 }
 
-// getHostSSHClient return ssh client with destination machine.
-func (k *Runtime) getHostSSHClient(hostIP net.IP) (ssh.Interface, error) {
-	return ssh.NewStdoutSSHClient(hostIP, k.cluster)
-}
-
 // getRootfs return the rootfs dir like: /var/lib/sealer/data/my-k0s-cluster/rootfs
 func (k *Runtime) getRootfs() string {
 	return common.DefaultTheClusterRootfsDir(k.cluster.Name)
@@ -153,14 +204,10 @@ func (k *Runtime) sendFileToHosts(Hosts []net.IP, src, dst string) error {
 	for _, node := range Hosts {
 		node := node
 		eg.Go(func() error {
-			sshClient, err := k.getHostSSHClient(node)
-			if err != nil {
+			if err := k.Runner.Copy(node, src, dst); err != nil {
 				return fmt.Errorf("failed to send file: %v", err)
 			}
-			if err := sshClient.Copy(node, src, dst); err != nil {
-				return fmt.Errorf("failed to send file: %v", err)
-			}
-			return err
+			return nil
 		})
 	}
 	return eg.Wait()
@@ -172,12 +219,7 @@ func (k *Runtime) WaitSSHReady(tryTimes int, hosts ...net.IP) error {
 		host := h
 		eg.Go(func() error {
 			for i := 0; i < tryTimes; i++ {
-				sshClient, err := k.getHostSSHClient(host)
-				if err != nil {
-					return err
-				}
-				err = sshClient.Ping(host)
-				if err == nil {
+				if err := k.Runner.Ping(host); err == nil {
 					return nil
 				}
 				time.Sleep(time.Duration(i) * time.Second)
@@ -190,10 +232,6 @@ func (k *Runtime) WaitSSHReady(tryTimes int, hosts ...net.IP) error {
 
 func (k *Runtime) CopyJoinToken(role string, hosts []net.IP) error {
 	var joinCertPath string
-	ssh, err := k.getHostSSHClient(k.cluster.GetMaster0IP())
-	if err != nil {
-		return err
-	}
 	switch role {
 	case ControllerRole:
 		joinCertPath = DefaultK0sControllerJoin
@@ -203,14 +241,86 @@ func (k *Runtime) CopyJoinToken(role string, hosts []net.IP) error {
 		joinCertPath = DefaultK0sWorkerJoin
 	}
 
+	master0 := k.cluster.GetMaster0IP()
 	eg, _ := errgroup.WithContext(context.Background())
 	for _, host := range hosts {
 		host := host
 		eg.Go(func() error {
-			return ssh.Copy(host, joinCertPath, joinCertPath)
+			if role != WorkerRole {
+				return k.Runner.Copy(host, joinCertPath, joinCertPath)
+			}
+			// workers join through the lvscare VIP instead of master0, so the token's
+			// embedded server address must be rewritten before it lands on the host.
+			return k.copyWorkerJoinTokenViaVIP(host, master0, joinCertPath)
 		})
 	}
-	return nil
+	return eg.Wait()
+}
+
+// copyWorkerJoinTokenViaVIP reads the worker join token from master0, rewrites its
+// embedded server address to the lvscare VIP (or to the local tunnel agent at
+// 127.0.0.1:6443 when the tunnel is enabled), and writes the result to host.
+func (k *Runtime) copyWorkerJoinTokenViaVIP(host, master0 net.IP, joinCertPath string) error {
+	token, err := k.Runner.Run(master0, fmt.Sprintf("cat %s", joinCertPath))
+	if err != nil {
+		return fmt.Errorf("failed to read join token: %v", err)
+	}
+
+	serverHost, serverPort := k.lvscare.VIP, k.lvscare.Port
+	if k.tunnelConfig().Enabled {
+		// the worker reaches the API server through its local tunnel agent instead of
+		// dialing the VIP directly.
+		serverHost, serverPort = "127.0.0.1", DefaultVIPPort
+	}
+
+	rewritten, err := rewriteJoinTokenServerAddress(token, serverHost, serverPort)
+	if err != nil {
+		return err
+	}
+
+	_, err = k.Runner.Run(host, fmt.Sprintf("cat > %s <<'EOF'\n%s\nEOF", joinCertPath, string(rewritten)))
+	return err
+}
+
+// reconcileLvscareManifests regenerates the lvscare static pod manifest from the
+// current controller set and pushes it to every worker's kubelet staticPodPath, so the
+// worker's own kubelet (configured for this path by JoinCommand) reconciles the
+// backend list whenever the controller set changes.
+func (k *Runtime) reconcileLvscareManifests(workers []net.IP) error {
+	if len(workers) == 0 {
+		return nil
+	}
+
+	manifest, err := k.lvscare.Manifest(k.cluster.GetMasterIPList())
+	if err != nil {
+		return fmt.Errorf("failed to render lvscare manifest: %v", err)
+	}
+
+	tmpFile, err := os.CreateTemp("", "lvscare-*.yaml")
+	if err != nil {
+		return fmt.Errorf("failed to create temp lvscare manifest: %v", err)
+	}
+	defer os.Remove(tmpFile.Name())
+
+	if _, err := tmpFile.Write(manifest); err != nil {
+		return fmt.Errorf("failed to write temp lvscare manifest: %v", err)
+	}
+	if err := tmpFile.Close(); err != nil {
+		return err
+	}
+
+	dst := filepath.Join(staticPodManifestDir, lvscareManifestName)
+	eg, _ := errgroup.WithContext(context.Background())
+	for _, h := range workers {
+		host := h
+		eg.Go(func() error {
+			if _, err := k.Runner.Run(host, fmt.Sprintf("mkdir -p %s", staticPodManifestDir)); err != nil {
+				return err
+			}
+			return k.Runner.Copy(host, tmpFile.Name(), dst)
+		})
+	}
+	return eg.Wait()
 }
 
 func (k *Runtime) JoinCommand(role string) []string {
@@ -221,7 +331,13 @@ func (k *Runtime) JoinCommand(role string) []string {
 				DefaultK0sControllerJoin, DefaultK0sConfigPath, ExternalCRI),
 			"k0s start",
 		},
-		WorkerRole: {fmt.Sprintf("k0s install worker --cri-socket %s --token-file %s", ExternalCRI, DefaultK0sWorkerJoin),
+		// --kubelet-extra-args sets the kubelet's staticPodPath explicitly to
+		// staticPodManifestDir: k0s does not point the worker kubelet there by
+		// default, and reconcileLvscareManifests needs a real static-pod directory
+		// the kubelet watches on its own, independent of the controller-mediated
+		// manifest deployer that only runs on controllers.
+		WorkerRole: {fmt.Sprintf("k0s install worker --cri-socket %s --token-file %s --kubelet-extra-args=\"--pod-manifest-path=%s\"",
+			ExternalCRI, DefaultK0sWorkerJoin, staticPodManifestDir),
 			"k0s start"},
 	}
 
@@ -229,16 +345,20 @@ func (k *Runtime) JoinCommand(role string) []string {
 	if !ok {
 		return nil
 	}
+
+	if role == WorkerRole && k.tunnelConfig().Enabled {
+		// the join token's server address was already rewritten to point here in
+		// CopyJoinToken; this makes the same intent explicit on the k0s install
+		// command line too.
+		v[0] = fmt.Sprintf("%s --server=https://%s", v[0], DefaultTunnelAgentLocalAddr)
+	}
+
 	return v
 }
 
 // CmdToString is in host exec cmd and replace to spilt str
 func (k *Runtime) CmdToString(host net.IP, cmd, split string) (string, error) {
-	ssh, err := k.getHostSSHClient(host)
-	if err != nil {
-		return "", fmt.Errorf("failed to get ssh clientof host(%s): %v", host, err)
-	}
-	return ssh.CmdToString(host, cmd, split)
+	return k.Runner.RunWithSplit(host, cmd, split)
 }
 
 func (k *Runtime) getClusterMetadata() (*runtime.Metadata, error) {
@@ -254,13 +374,16 @@ func (k *Runtime) getClusterMetadata() (*runtime.Metadata, error) {
 }
 
 func (k *Runtime) getKubeVersion() (string, error) {
-	ssh, err := k.getHostSSHClient(k.cluster.GetMaster0IP())
-	if err != nil {
-		return "", err
-	}
-	bytes, err := ssh.Cmd(k.cluster.GetMaster0IP(), VersionCmd)
+	return k.getKubeVersionFromHost(k.cluster.GetMaster0IP())
+}
+
+// getKubeVersionFromHost runs VersionCmd on host directly, split out from
+// getKubeVersion so it can be exercised with a FakeRunner without constructing a full
+// cluster.
+func (k *Runtime) getKubeVersionFromHost(host net.IP) (string, error) {
+	out, err := k.Runner.Run(host, VersionCmd)
 	if err != nil {
 		return "", err
 	}
-	return strings.Split(string(bytes), "+")[0], nil
+	return strings.Split(string(out), "+")[0], nil
 }
@@ -0,0 +1,104 @@
+// Copyright © 2022 Alibaba Group Holding Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package k0s
+
+import (
+	"fmt"
+	"net"
+	"strings"
+)
+
+const (
+	// DefaultLvscareImage is the image used to run the lvscare static pod that load
+	// balances worker traffic across every controller.
+	DefaultLvscareImage = "sealerio/lvscare:latest"
+	// DefaultVIP is the virtual IP the lvscare static pod listens on for the k0s API
+	// server.
+	DefaultVIP = "10.103.97.2"
+	// DefaultVIPPort is the port exposed on DefaultVIP, matching the k0s API server
+	// port.
+	DefaultVIPPort = "6443"
+	// staticPodManifestDir is the directory the worker's own kubelet watches for
+	// static pod manifests. It must be the kubelet's local staticPodPath, not the k0s
+	// controller-mediated manifest deployer under /var/lib/k0s/manifests: that
+	// deployer only runs on controllers and applies through the API server, whereas
+	// lvscare has to come up on every worker, including before that worker has ever
+	// reached the API server through the VIP lvscare itself provides. JoinCommand
+	// passes this same path to the worker's kubelet via --pod-manifest-path so it is
+	// actually watched.
+	staticPodManifestDir = "/etc/k0s/manifests"
+	// lvscareManifestName is the manifest file name written into staticPodManifestDir.
+	lvscareManifestName = "lvscare.yaml"
+)
+
+// lvscareManifestTemplate renders a static pod that runs lvscare in IPVS mode,
+// watching the real controller endpoints and exposing a fixed VIP:port.
+const lvscareManifestTemplate = `apiVersion: v1
+kind: Pod
+metadata:
+  name: lvscare
+  namespace: kube-system
+  labels:
+    app: lvscare
+spec:
+  hostNetwork: true
+  containers:
+  - name: lvscare
+    image: %s
+    args:
+%s
+    securityContext:
+      privileged: true
+`
+
+// StaticPodGenerator renders the lvscare static pod manifest that k0s picks up from
+// /etc/k0s/manifests. lvscare runs an IPVS load balancer in front of every controller
+// so workers join and talk to a single VIP instead of a single controller IP.
+type StaticPodGenerator struct {
+	Image string
+	VIP   string
+	Port  string
+}
+
+// NewStaticPodGenerator returns a StaticPodGenerator for the given VIP and port,
+// falling back to DefaultVIP/DefaultVIPPort when either is empty.
+func NewStaticPodGenerator(vip, port string) *StaticPodGenerator {
+	if vip == "" {
+		vip = DefaultVIP
+	}
+	if port == "" {
+		port = DefaultVIPPort
+	}
+	return &StaticPodGenerator{
+		Image: DefaultLvscareImage,
+		VIP:   vip,
+		Port:  port,
+	}
+}
+
+// Manifest renders the lvscare static pod manifest watching controllers and exposing
+// g.VIP:g.Port as the virtual server.
+func (g *StaticPodGenerator) Manifest(controllers []net.IP) ([]byte, error) {
+	if len(controllers) == 0 {
+		return nil, fmt.Errorf("lvscare manifest requires at least one controller IP")
+	}
+
+	args := []string{"    - static", fmt.Sprintf("    - --vs=%s:%s", g.VIP, g.Port)}
+	for _, c := range controllers {
+		args = append(args, fmt.Sprintf("    - --rs=%s:%s", c.String(), g.Port))
+	}
+
+	return []byte(fmt.Sprintf(lvscareManifestTemplate, g.Image, strings.Join(args, "\n"))), nil
+}
@@ -0,0 +1,176 @@
+// Copyright © 2022 Alibaba Group Holding Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package k0s
+
+import (
+	"fmt"
+	"net"
+
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	// DefaultTunnelAgentLocalAddr is where the tunnel agent exposes a local proxy to
+	// the real k0s API server, for workers that cannot dial the controller VIP
+	// directly (NAT'd edge nodes, split VPCs).
+	DefaultTunnelAgentLocalAddr = "127.0.0.1:6443"
+	// tunnelAgentServiceName is the systemd unit installed on workers to run the agent.
+	tunnelAgentServiceName = "k0s-tunnel-agent"
+	// tunnelAgentBinPath is where the agent binary is installed on the worker.
+	tunnelAgentBinPath = "/usr/local/bin/k0s-tunnel-agent"
+	// tunnelAgentLocalBinaryPath is where sealer expects to find the k0s-tunnel-agent
+	// binary (built from cmd/k0s-tunnel-agent) to push to workers, mirroring how
+	// other static binaries (sealctl) are shipped inside the cluster image rootfs.
+	tunnelAgentLocalBinaryPath = "bin/k0s-tunnel-agent"
+	// tunnelServerServiceName is the systemd unit installed on controllers to run the
+	// tunnel server.
+	tunnelServerServiceName = "k0s-tunnel-server"
+	// tunnelServerBinPath is where the server binary is installed on the controller.
+	tunnelServerBinPath = "/usr/local/bin/k0s-tunnel-server"
+	// tunnelServerLocalBinaryPath is where sealer expects to find the
+	// k0s-tunnel-server binary (built from cmd/k0s-tunnel-server) to push to
+	// controllers, mirroring tunnelAgentLocalBinaryPath.
+	tunnelServerLocalBinaryPath = "bin/k0s-tunnel-server"
+	// tunnelUpstreamAddr is the real k0s API server the tunnel server forwards
+	// tunneled connections to; it runs on the same controller as the server.
+	tunnelUpstreamAddr = "127.0.0.1:6443"
+)
+
+// tunnelAgentUnitTemplate is the systemd unit installed on a worker by
+// installTunnelAgent. The agent (pkg/tunnel.Agent) multiplexes the connection to the
+// controller with yamux and reconnects with exponential backoff on its own;
+// Restart=always just keeps the process alive across crashes.
+const tunnelAgentUnitTemplate = `[Unit]
+Description=k0s tunnel agent
+After=network-online.target
+
+[Service]
+ExecStart=%s --server=%s --token-file=%s --listen=%s
+Restart=always
+RestartSec=2
+
+[Install]
+WantedBy=multi-user.target
+`
+
+// tunnelServerUnitTemplate is the systemd unit installed on a controller by
+// installTunnelServer, running pkg/tunnel.Server to accept agent connections and
+// forward them to the real API server on the same host.
+const tunnelServerUnitTemplate = `[Unit]
+Description=k0s tunnel server
+After=network-online.target
+
+[Service]
+ExecStart=%s --listen=%s --upstream=%s
+Restart=always
+RestartSec=2
+
+[Install]
+WantedBy=multi-user.target
+`
+
+// TunnelConfig holds the reverse-tunnel settings read from Spec.Network.Tunnel. It is
+// optional: when Enabled is false, JoinNodes behaves exactly as before and workers dial
+// the lvscare VIP directly.
+type TunnelConfig struct {
+	// Enabled turns on the reverse tunnel transport for JoinNodes.
+	Enabled bool
+	// ServerAddr is the controller-side tunnel server workers dial, host:port.
+	ServerAddr string
+}
+
+// tunnelConfig reads the optional reverse-tunnel settings from the ClusterFile.
+func (k *Runtime) tunnelConfig() TunnelConfig {
+	if k.cluster == nil {
+		return TunnelConfig{}
+	}
+	tunnel := k.cluster.Spec.Network.Tunnel
+	return TunnelConfig{
+		Enabled:    tunnel.Enabled,
+		ServerAddr: tunnel.ServerAddr,
+	}
+}
+
+// installTunnelAgent pushes the k0s-tunnel-agent binary (cmd/k0s-tunnel-agent,
+// pkg/tunnel.Agent) to host and enables it as a systemd unit, so the worker exposes
+// DefaultTunnelAgentLocalAddr proxying through the tunnel to the real API server on
+// the controller. Installed through the same CommandRunner the rest of the join flow
+// uses, so it works under SSH, local exec, or any future transport.
+func (k *Runtime) installTunnelAgent(host net.IP, cfg TunnelConfig) error {
+	if !cfg.Enabled {
+		return nil
+	}
+	if cfg.ServerAddr == "" {
+		return fmt.Errorf("tunnel server address is required when Spec.Network.Tunnel is enabled")
+	}
+
+	if err := k.Runner.Copy(host, tunnelAgentLocalBinaryPath, tunnelAgentBinPath); err != nil {
+		return fmt.Errorf("failed to push tunnel agent to %s: %v", host, err)
+	}
+
+	unit := fmt.Sprintf(tunnelAgentUnitTemplate, tunnelAgentBinPath, cfg.ServerAddr, DefaultK0sWorkerJoin, DefaultTunnelAgentLocalAddr)
+	cmds := []string{
+		fmt.Sprintf("cat > /etc/systemd/system/%s.service <<'EOF'\n%s\nEOF", tunnelAgentServiceName, unit),
+		"systemctl daemon-reload",
+		fmt.Sprintf("systemctl enable --now %s", tunnelAgentServiceName),
+	}
+	for _, cmd := range cmds {
+		if _, err := k.Runner.Run(host, cmd); err != nil {
+			return fmt.Errorf("failed to enable tunnel agent on %s: %v", host, err)
+		}
+	}
+
+	logrus.Infof("enabled %s on %s, proxying %s through %s", tunnelAgentServiceName, host, DefaultTunnelAgentLocalAddr, cfg.ServerAddr)
+	return nil
+}
+
+// installTunnelServer pushes the k0s-tunnel-server binary (cmd/k0s-tunnel-server,
+// pkg/tunnel.Server) to a controller host and enables it as a systemd unit, so it can
+// accept connections from installTunnelAgent on workers and forward them to the API
+// server running on that same controller.
+func (k *Runtime) installTunnelServer(host net.IP, cfg TunnelConfig) error {
+	if !cfg.Enabled {
+		return nil
+	}
+
+	_, port, err := net.SplitHostPort(cfg.ServerAddr)
+	if err != nil {
+		return fmt.Errorf("invalid tunnel server address %q: %v", cfg.ServerAddr, err)
+	}
+	// the server listens on every interface on the tunnel port: cfg.ServerAddr is
+	// whatever address workers dial (a single controller, or the lvscare VIP load
+	// balancing across all of them), which isn't necessarily an address this specific
+	// controller owns.
+	listenAddr := fmt.Sprintf("0.0.0.0:%s", port)
+
+	if err := k.Runner.Copy(host, tunnelServerLocalBinaryPath, tunnelServerBinPath); err != nil {
+		return fmt.Errorf("failed to push tunnel server to %s: %v", host, err)
+	}
+
+	unit := fmt.Sprintf(tunnelServerUnitTemplate, tunnelServerBinPath, listenAddr, tunnelUpstreamAddr)
+	cmds := []string{
+		fmt.Sprintf("cat > /etc/systemd/system/%s.service <<'EOF'\n%s\nEOF", tunnelServerServiceName, unit),
+		"systemctl daemon-reload",
+		fmt.Sprintf("systemctl enable --now %s", tunnelServerServiceName),
+	}
+	for _, cmd := range cmds {
+		if _, err := k.Runner.Run(host, cmd); err != nil {
+			return fmt.Errorf("failed to enable tunnel server on %s: %v", host, err)
+		}
+	}
+
+	logrus.Infof("enabled %s on %s, forwarding tunneled connections to %s", tunnelServerServiceName, host, tunnelUpstreamAddr)
+	return nil
+}
@@ -0,0 +1,144 @@
+// Copyright © 2022 Alibaba Group Holding Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tunnel
+
+import (
+	"fmt"
+	"net"
+	"time"
+
+	"github.com/hashicorp/yamux"
+	"github.com/sirupsen/logrus"
+)
+
+// minBackoff/maxBackoff bound Agent's reconnect delay: it starts fast (the controller
+// is usually just restarting) and caps out so a prolonged outage doesn't hammer it.
+const (
+	minBackoff = time.Second
+	maxBackoff = 30 * time.Second
+)
+
+// Agent dials ServerAddr (the controller-side tunnel Server) and exposes Listen as a
+// local proxy to the real API server on the other end of the tunnel. It reconnects
+// with exponential backoff if the session drops, so it tolerates controller restarts
+// and transient network loss without external supervision beyond "keep the process
+// running" (a systemd unit with Restart=always).
+type Agent struct {
+	// ServerAddr is the controller-side tunnel Server to dial, host:port.
+	ServerAddr string
+	// Listen is the local address the agent listens on, normally
+	// DefaultTunnelAgentLocalAddr (127.0.0.1:6443), proxying to the real API server.
+	Listen string
+}
+
+// Run dials ServerAddr and serves Listen until ctx-less permanent failure; it only
+// returns on a local listener error, reconnecting to ServerAddr indefinitely on every
+// other failure.
+func (a *Agent) Run() error {
+	ln, err := net.Listen("tcp", a.Listen)
+	if err != nil {
+		return fmt.Errorf("tunnel agent failed to listen on %s: %v", a.Listen, err)
+	}
+	defer ln.Close()
+
+	backoff := minBackoff
+	for {
+		session, err := a.connect()
+		if err != nil {
+			logrus.Warnf("tunnel agent: failed to connect to %s, retrying in %s: %v", a.ServerAddr, backoff, err)
+			time.Sleep(backoff)
+			backoff = nextBackoff(backoff)
+			continue
+		}
+		backoff = minBackoff
+
+		if err := a.serve(ln, session); err != nil {
+			return err
+		}
+		// serve returns nil once the session has died; loop around to reconnect.
+	}
+}
+
+// connect dials ServerAddr and wraps the connection as a yamux client session.
+func (a *Agent) connect() (*yamux.Session, error) {
+	conn, err := net.DialTimeout("tcp", a.ServerAddr, 10*time.Second)
+	if err != nil {
+		return nil, err
+	}
+	return yamux.Client(conn, nil)
+}
+
+// serve accepts local connections on ln and proxies each over a new stream on
+// session, returning nil when session dies and an error when the local listener fails.
+func (a *Agent) serve(ln net.Listener, session *yamux.Session) error {
+	defer session.Close()
+
+	go func() {
+		// session.Accept never returns a stream the agent side opens (only the server
+		// opens streams toward it), so this just blocks until the session closes, at
+		// which point every local conn being served will also fail and unwind.
+		<-session.CloseChan()
+	}()
+
+	type acceptResult struct {
+		conn net.Conn
+		err  error
+	}
+	accepted := make(chan acceptResult)
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			accepted <- acceptResult{conn, err}
+			if err != nil {
+				return
+			}
+		}
+	}()
+
+	for {
+		select {
+		case <-session.CloseChan():
+			return nil
+		case res := <-accepted:
+			if res.err != nil {
+				return fmt.Errorf("tunnel agent: local listener error: %v", res.err)
+			}
+			go a.proxy(res.conn, session)
+		}
+	}
+}
+
+// proxy opens a new stream on session and pipes bytes between it and conn.
+func (a *Agent) proxy(conn net.Conn, session *yamux.Session) {
+	defer conn.Close()
+
+	stream, err := session.Open()
+	if err != nil {
+		logrus.Errorf("tunnel agent: failed to open stream: %v", err)
+		return
+	}
+	defer stream.Close()
+
+	pipe(conn, stream)
+}
+
+// nextBackoff doubles d, capped at maxBackoff.
+func nextBackoff(d time.Duration) time.Duration {
+	d *= 2
+	if d > maxBackoff {
+		return maxBackoff
+	}
+	return d
+}
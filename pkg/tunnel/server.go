@@ -0,0 +1,116 @@
+// Copyright © 2022 Alibaba Group Holding Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package tunnel implements the reverse tunnel that lets a worker behind NAT (or a
+// split VPC that cannot route to the controller) reach the k0s API server anyway.
+//
+// The worker can always dial out to the controller, so the Agent initiates the
+// connection and the Server just accepts it. Once connected, the two multiplex
+// arbitrarily many logical streams over that single TCP connection with yamux: every
+// local connection the Agent accepts on the worker is opened as a new yamux stream and
+// piped to a connection the Server dials against the real API server on the
+// controller. Neither side needs the other to be reachable first, which is the whole
+// point versus a plain reverse proxy.
+package tunnel
+
+import (
+	"fmt"
+	"io"
+	"net"
+
+	"github.com/hashicorp/yamux"
+	"github.com/sirupsen/logrus"
+)
+
+// Server accepts Agent connections on Addr and, for every yamux stream an Agent opens,
+// dials Upstream and pipes bytes between the two until either side closes.
+type Server struct {
+	// Addr is the host:port the server listens on for incoming Agent connections.
+	Addr string
+	// Upstream is dialed for every stream an Agent opens, normally the controller's
+	// own k0s API server (e.g. 127.0.0.1:6443).
+	Upstream string
+}
+
+// ListenAndServe accepts Agent connections on s.Addr until it returns an error.
+// Each accepted connection is served in its own goroutine, so one worker's tunnel
+// cannot block another's.
+func (s *Server) ListenAndServe() error {
+	ln, err := net.Listen("tcp", s.Addr)
+	if err != nil {
+		return fmt.Errorf("tunnel server failed to listen on %s: %v", s.Addr, err)
+	}
+	defer ln.Close()
+
+	logrus.Infof("tunnel server listening on %s, forwarding to %s", s.Addr, s.Upstream)
+	for {
+		conn, err := ln.Accept()
+		if err != nil {
+			return err
+		}
+		go s.serve(conn)
+	}
+}
+
+// serve multiplexes conn as a yamux server session and proxies every stream the peer
+// opens to s.Upstream.
+func (s *Server) serve(conn net.Conn) {
+	session, err := yamux.Server(conn, nil)
+	if err != nil {
+		logrus.Errorf("tunnel server: failed to set up session with %s: %v", conn.RemoteAddr(), err)
+		conn.Close()
+		return
+	}
+	defer session.Close()
+
+	for {
+		stream, err := session.Accept()
+		if err != nil {
+			// the agent disconnected (or will reconnect); nothing left to do with
+			// this session.
+			return
+		}
+		go s.proxy(stream)
+	}
+}
+
+// proxy dials s.Upstream and pipes bytes between it and stream until either side
+// closes.
+func (s *Server) proxy(stream net.Conn) {
+	defer stream.Close()
+
+	upstream, err := net.Dial("tcp", s.Upstream)
+	if err != nil {
+		logrus.Errorf("tunnel server: failed to dial upstream %s: %v", s.Upstream, err)
+		return
+	}
+	defer upstream.Close()
+
+	pipe(stream, upstream)
+}
+
+// pipe copies a<->b until both directions have finished.
+func pipe(a, b net.Conn) {
+	done := make(chan struct{}, 2)
+	go func() {
+		io.Copy(a, b) //nolint:errcheck
+		done <- struct{}{}
+	}()
+	go func() {
+		io.Copy(b, a) //nolint:errcheck
+		done <- struct{}{}
+	}()
+	<-done
+	<-done
+}
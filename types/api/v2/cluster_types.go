@@ -0,0 +1,103 @@
+// Copyright © 2022 Alibaba Group Holding Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package v2
+
+import "net"
+
+const (
+	// MASTER is the Host.Roles entry marking a host as a control-plane node.
+	MASTER = "master"
+	// NODE is the Host.Roles entry marking a host as a worker node.
+	NODE = "node"
+)
+
+// Cluster is the in-memory form of a sealer ClusterFile.
+type Cluster struct {
+	Name string
+	Spec ClusterSpec
+}
+
+// ClusterSpec is the part of the ClusterFile that describes the desired cluster.
+type ClusterSpec struct {
+	Hosts []Host
+	// SANs are extra DNS names/IPs merged into the k0s API server serving cert's SAN
+	// list on top of whatever k0s itself derives from Hosts, so a rotated cert keeps
+	// covering names such as an externally provisioned load balancer.
+	SANs []string
+	// Network carries cluster networking settings, including the optional reverse
+	// tunnel transport used by workers that cannot dial the API server directly.
+	Network NetworkConfig
+}
+
+// Host describes one node in the cluster and the role(s) it plays.
+type Host struct {
+	IPS   []net.IP
+	Roles []string
+}
+
+// NetworkConfig groups the cluster's networking settings.
+type NetworkConfig struct {
+	// Tunnel configures the optional reverse tunnel transport. Zero value leaves it
+	// disabled, so existing ClusterFiles keep working unchanged.
+	Tunnel TunnelConfig
+}
+
+// TunnelConfig is the reverse-tunnel settings read by the k0s Runtime's
+// tunnelConfig().
+type TunnelConfig struct {
+	// Enabled turns on the reverse tunnel transport for JoinNodes.
+	Enabled bool
+	// ServerAddr is the controller-side tunnel server workers dial, host:port.
+	ServerAddr string
+}
+
+// GetMasterIPList returns the IPs of every host with the MASTER role.
+func (c *Cluster) GetMasterIPList() []net.IP {
+	return c.getIPListByRole(MASTER)
+}
+
+// GetNodeIPList returns the IPs of every host with the NODE role.
+func (c *Cluster) GetNodeIPList() []net.IP {
+	return c.getIPListByRole(NODE)
+}
+
+// GetMaster0IP returns the first master IP, or nil if there is none.
+func (c *Cluster) GetMaster0IP() net.IP {
+	masters := c.GetMasterIPList()
+	if len(masters) == 0 {
+		return nil
+	}
+	return masters[0]
+}
+
+func (c *Cluster) getIPListByRole(role string) []net.IP {
+	var ips []net.IP
+	for _, h := range c.Spec.Hosts {
+		if !hasRole(h.Roles, role) {
+			continue
+		}
+		ips = append(ips, h.IPS...)
+	}
+	return ips
+}
+
+func hasRole(roles []string, role string) bool {
+	for _, r := range roles {
+		if r == role {
+			return true
+		}
+	}
+	return false
+}